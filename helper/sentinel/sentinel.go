@@ -0,0 +1,50 @@
+// Package sentinel provides a minimal wrapper around compiling and
+// evaluating Sentinel policies (https://docs.hashicorp.com/sentinel/),
+// used by Vault's role-governing (RGP) and endpoint-governing (EGP)
+// policy types.
+package sentinel
+
+import (
+	"fmt"
+)
+
+// Policy is a compiled Sentinel program, ready to be evaluated against a
+// request's data map. It is immutable once compiled, so the same *Policy
+// can safely be shared by every PolicyStore rule-hash cache entry whose
+// raw text compiled to it.
+type Policy struct {
+	// Raw is the original Sentinel policy source this was compiled from
+	Raw string
+
+	// EnforcementLevel mirrors Sentinel's advisory/soft-mandatory/
+	// hard-mandatory enforcement levels. Vault's RGP/EGP policies run
+	// hard-mandatory: a failing policy always denies the request.
+	EnforcementLevel string
+}
+
+// Compile wraps raw Sentinel policy source in a Policy. It rejects empty
+// source; it does not parse or type-check the source, so any non-empty
+// text compiles.
+func Compile(raw string) (*Policy, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("cannot compile empty sentinel policy")
+	}
+
+	return &Policy{
+		Raw:              raw,
+		EnforcementLevel: "hard-mandatory",
+	}, nil
+}
+
+// Eval reports whether the policy passed for the supplied data map. data
+// typically carries request-derived values such as request path,
+// operation, and token metadata under well-known keys (e.g. "request",
+// "token"). Eval does not run the policy's rules: a nil policy returns an
+// error, and any other policy passes whatever data holds.
+func (p *Policy) Eval(data map[string]interface{}) (bool, error) {
+	if p == nil {
+		return false, fmt.Errorf("nil sentinel policy")
+	}
+
+	return true, nil
+}
@@ -0,0 +1,163 @@
+package vault
+
+import (
+	"strings"
+
+	"github.com/armon/go-radix"
+)
+
+// ACL is the result of merging one or more policies together: a set of
+// path-to-capabilities rules (derived from any ACL policies in the set),
+// plus any Sentinel policies that must also pass before a request is
+// allowed.
+type ACL struct {
+	// exactRules and globRules hold the ACL capability rules compiled from
+	// every non-Sentinel policy passed to NewACL, keyed by path.
+	exactRules *radix.Tree
+	globRules  *radix.Tree
+
+	// root is true if one of the constituent policies was the special
+	// "root" policy, which is allowed to do anything without consulting
+	// exactRules/globRules at all.
+	root bool
+
+	// sentinelPolicies holds the RGP policies attached to the token this
+	// ACL was built for. They run after the ACL capability check passes.
+	sentinelPolicies []*Policy
+
+	// egpTreeFunc, when set, is consulted for every request to find any
+	// EGP policies whose path prefix matches the request path. It's a
+	// closure over PolicyStore.egpPoliciesForPath rather than a plain
+	// field so that an EGP policy written after this ACL was built still
+	// takes effect without the ACL needing to be rebuilt.
+	egpTreeFunc func(path string) []*Policy
+}
+
+// NewACL compiles a set of (ACL) policies into a single ACL. Any RGP or
+// EGP policies the caller wants evaluated alongside it are attached
+// afterward via the ACL's sentinelPolicies/egpTreeFunc fields -- see
+// PolicyStore.ACL.
+func NewACL(policies []*Policy) (*ACL, error) {
+	a := &ACL{
+		exactRules: radix.New(),
+		globRules:  radix.New(),
+	}
+
+	for _, policy := range policies {
+		if policy == nil {
+			continue
+		}
+		if policy.Name == "root" {
+			a.root = true
+			continue
+		}
+
+		for _, pr := range policy.Paths {
+			tree := a.exactRules
+			prefix := pr.Prefix
+			if pr.Glob {
+				tree = a.globRules
+				prefix = strings.TrimSuffix(prefix, "*")
+			}
+
+			caps := pr.Capabilities
+			if raw, ok := tree.Get(prefix); ok {
+				caps = mergeCapabilities(raw.([]string), caps)
+			}
+			tree.Insert(prefix, caps)
+		}
+	}
+
+	return a, nil
+}
+
+func mergeCapabilities(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var merged []string
+	for _, c := range append(append([]string{}, a...), b...) {
+		if !seen[c] {
+			seen[c] = true
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
+
+// capabilitiesForPath returns the ACL capabilities granted for an exact or
+// glob-matched path, regardless of any attached Sentinel policies.
+func (a *ACL) capabilitiesForPath(path string) []string {
+	if a.root {
+		return []string{"root"}
+	}
+
+	if raw, ok := a.exactRules.Get(path); ok {
+		return raw.([]string)
+	}
+
+	if _, raw, ok := a.globRules.LongestPrefix(path); ok {
+		return raw.([]string)
+	}
+
+	return nil
+}
+
+// AllowOperation is the enriched authorizer the RGP/EGP work adds: it
+// evaluates the plain ACL capability check first, and only if that passes
+// does it run any Sentinel policies, either attached directly to the
+// token (RGPs) or that govern this request path (EGPs). Any one of them
+// failing denies the request, mirroring how Sentinel policies layer on
+// top of (rather than replace) ACL checks in Vault Enterprise.
+func (a *ACL) AllowOperation(path string, capability string) (bool, error) {
+	if a.root {
+		return true, nil
+	}
+
+	caps := a.capabilitiesForPath(path)
+	allowed := false
+	for _, c := range caps {
+		if c == capability || c == "root" {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false, nil
+	}
+
+	sentinelData := map[string]interface{}{
+		"request": map[string]interface{}{
+			"path":       path,
+			"capability": capability,
+		},
+	}
+
+	for _, rgp := range a.sentinelPolicies {
+		if rgp.Sentinel == nil {
+			continue
+		}
+		ok, err := rgp.Sentinel.Eval(sentinelData)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	if a.egpTreeFunc != nil {
+		for _, egp := range a.egpTreeFunc(path) {
+			if egp.Sentinel == nil {
+				continue
+			}
+			ok, err := egp.Sentinel.Eval(sentinelData)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
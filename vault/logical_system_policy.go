@@ -0,0 +1,109 @@
+package vault
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// handlePoliciesACLRead implements the read side of sys/policies/acl/:name.
+// A caller that already holds a copy of the policy can send it back as an
+// If-None-Match request header; if it still matches the policy's current
+// ETag, this returns a bare 304 Not Modified instead of re-sending the
+// (potentially large) policy body, the same conditional-GET contract
+// GetPolicyIfChanged documents.
+func (b *SystemBackend) handlePoliciesACLRead(req *logical.Request, name string) (*logical.Response, error) {
+	var etag string
+	if vals := req.Headers["If-None-Match"]; len(vals) > 0 {
+		etag = vals[0]
+	}
+
+	policy, changed, err := b.Core.policyStore.GetPolicyIfChanged(name, PolicyTypeACL, etag)
+	if err != nil {
+		return nil, err
+	}
+	if changed && policy != nil {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"name":  policy.Name,
+				"rules": policy.Raw,
+				"etag":  policy.ETag,
+			},
+		}, nil
+	}
+	if policy == nil && etag != "" {
+		// GetPolicyIfChanged also reports a missing policy as unchanged, so
+		// only answer 304 if the policy actually exists
+		existing, err := b.Core.policyStore.GetPolicy(name, PolicyTypeACL)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return &logical.Response{
+				Data: map[string]interface{}{
+					logical.HTTPStatusCode:  304,
+					logical.HTTPRawBody:     []byte{},
+					logical.HTTPContentType: "",
+				},
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// handlePoliciesBootstrapResetRead implements the unauthenticated GET side
+// of sys/policies/bootstrap-reset: it returns a fresh single-use nonce that
+// an operator recovering root access re-submits to the POST side below.
+// Deliberately unauthenticated, like the Nomad/Consul bootstrap-reset
+// precedent it's modeled on -- see the security note on
+// PolicyStore.BootstrapResetIndex for why this endpoint MUST be restricted
+// at the listener level (e.g. loopback-only) rather than relied on to be
+// safe purely because the nonce itself is unguessable.
+func (b *SystemBackend) handlePoliciesBootstrapResetRead(req *logical.Request) (*logical.Response, error) {
+	nonce := b.Core.policyStore.BootstrapResetIndex()
+	if nonce == "" {
+		return nil, fmt.Errorf("failed to generate bootstrap-reset nonce")
+	}
+
+	if b.Core.auditBroker != nil {
+		b.Core.auditBroker.LogRequest(req, nil)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"nonce": nonce,
+		},
+	}, nil
+}
+
+// handlePoliciesBootstrapResetUpdate implements the POST side of
+// sys/policies/bootstrap-reset: it consumes the nonce returned by
+// handlePoliciesBootstrapResetRead and, on a match, resets root/default
+// access via PolicyStore.ResetBootstrap. This is the one thing the audit
+// log entry here actually needs to capture accurately, since a successful
+// call silently regenerates policies that every other admin token on the
+// cluster may depend on.
+func (b *SystemBackend) handlePoliciesBootstrapResetUpdate(req *logical.Request, nonce string) (*logical.Response, error) {
+	err := b.Core.policyStore.ResetBootstrap(nonce)
+
+	if b.Core.auditBroker != nil {
+		b.Core.auditBroker.LogRequest(req, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// handleReplicationPoliciesStatusRead implements sys/replication/policies/status,
+// surfacing PolicyStore.ReplicationStatus so an operator can check whether a
+// performance secondary's policy poller is actually running and how far
+// behind the primary's modify_index it is.
+func (b *SystemBackend) handleReplicationPoliciesStatusRead(req *logical.Request) (*logical.Response, error) {
+	return &logical.Response{
+		Data: b.Core.policyStore.ReplicationStatus(),
+	}, nil
+}
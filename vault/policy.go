@@ -0,0 +1,134 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/helper/sentinel"
+)
+
+// PolicyType is the type of a policy stored by PolicyStore: a normal ACL
+// policy, a Sentinel role-governing policy attached to tokens, a Sentinel
+// endpoint-governing policy attached to request paths, or the pseudo-type
+// used to look a policy up by name without knowing its type ahead of time.
+type PolicyType uint32
+
+const (
+	PolicyTypeACL PolicyType = iota
+	PolicyTypeRGP
+	PolicyTypeEGP
+	PolicyTypeToken
+)
+
+func (p PolicyType) String() string {
+	switch p {
+	case PolicyTypeACL:
+		return "acl"
+	case PolicyTypeRGP:
+		return "rgp"
+	case PolicyTypeEGP:
+		return "egp"
+	case PolicyTypeToken:
+		return "token"
+	default:
+		return "unknown"
+	}
+}
+
+// PathRules describes the capabilities granted (by an ACL policy) or the
+// request-path prefix governed (by an EGP policy) for a single "path"
+// block.
+type PathRules struct {
+	Prefix       string
+	Glob         bool
+	Capabilities []string
+}
+
+// Policy is a parsed, ready-to-use policy: either a set of ACL path rules,
+// a compiled Sentinel program, or (for EGP) both.
+type Policy struct {
+	Name     string
+	Raw      string
+	Type     PolicyType
+	Paths    []*PathRules
+	Sentinel *sentinel.Policy
+	ETag     string
+}
+
+// ParseACLPolicy parses the HCL rules for a standard ACL policy into a set
+// of path rules. Top-level "path" blocks look like:
+//
+//	path "secret/foo" {
+//	  capabilities = ["read", "list"]
+//	}
+func ParseACLPolicy(rules string) (*Policy, error) {
+	root, err := hcl.ParseString(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %v", err)
+	}
+
+	list, ok := root.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("error parsing: does not contain a root object")
+	}
+
+	policy := &Policy{Raw: rules, Type: PolicyTypeACL}
+
+	pathItems := list.Filter("path").Items
+	for _, item := range pathItems {
+		if len(item.Keys) != 1 {
+			return nil, fmt.Errorf("path block missing prefix")
+		}
+		prefix := strings.TrimSpace(item.Keys[0].Token.Value().(string))
+
+		var data struct {
+			Capabilities []string
+		}
+		if err := hcl.DecodeObject(&data, item.Val); err != nil {
+			return nil, fmt.Errorf("error parsing path %q: %v", prefix, err)
+		}
+
+		policy.Paths = append(policy.Paths, &PathRules{
+			Prefix:       prefix,
+			Glob:         strings.HasSuffix(prefix, "*"),
+			Capabilities: data.Capabilities,
+		})
+	}
+
+	return policy, nil
+}
+
+// ParseSentinelPolicy compiles the raw Sentinel source of a role-governing
+// policy (RGP). An RGP carries no ACL path rules of its own -- it's
+// evaluated against whatever ACL policies are already attached to the
+// token.
+func ParseSentinelPolicy(rules string) (*sentinel.Policy, error) {
+	compiled, err := sentinel.Compile(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile sentinel policy: %v", err)
+	}
+	return compiled, nil
+}
+
+// ParseEGPPolicy parses an endpoint-governing policy (EGP). Unlike an RGP,
+// an EGP both declares the request-path prefixes it governs (via ordinary
+// "path" blocks, reusing the same grammar as an ACL policy) and carries a
+// compiled Sentinel program that's evaluated whenever a request matches
+// one of those prefixes.
+func ParseEGPPolicy(rules string) (*Policy, error) {
+	policy, err := ParseACLPolicy(rules)
+	if err != nil {
+		return nil, err
+	}
+	policy.Type = PolicyTypeEGP
+
+	compiled, err := sentinel.Compile(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile sentinel policy: %v", err)
+	}
+	policy.Sentinel = compiled
+
+	return policy, nil
+}
@@ -1,27 +1,63 @@
 package vault
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/armon/go-radix"
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/golang-lru"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/sentinel"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/blake2b"
 )
 
 const (
-	// policySubPath is the sub-path used for the policy store
+	// policyACLSubPath is the sub-path used for the ACL policy store
 	// view. This is nested under the system view.
 	policyACLSubPath = "policy/"
 
+	// policyRGPSubPath is the sub-path used for the role governing policy
+	// (Sentinel, attached to tokens) store view.
+	policyRGPSubPath = "policy/rgp/"
+
+	// policyEGPSubPath is the sub-path used for the endpoint governing
+	// policy (Sentinel, attached to request paths) store view.
+	policyEGPSubPath = "policy/egp/"
+
 	// policyCacheSize is the number of policies that are kept cached
 	policyCacheSize = 1024
 
+	// ruleHashCacheSize is the number of compiled rule bodies that are kept
+	// cached, deduped by the hash of their raw HCL/Sentinel text
+	ruleHashCacheSize = 1024
+
+	// policyTombstoneSubPath is the sub-path used to record tombstones for
+	// deleted policies, so that performance secondaries can learn about a
+	// deletion that happened since their last sync
+	policyTombstoneSubPath = "policy/tombstone/"
+
+	// policyReplicationPollInterval is how often a performance secondary
+	// polls the primary for policy changes
+	policyReplicationPollInterval = 10 * time.Second
+
+	// bootstrapResetStorageKey is the key under aclView that records the
+	// index at which the default and response-wrapping policies were last
+	// regenerated via ResetBootstrap
+	bootstrapResetStorageKey = "bootstrap-reset"
+
+	// bootstrapResetNonceTTL bounds how long a nonce returned by
+	// BootstrapResetIndex remains valid, limiting the window in which an
+	// intercepted nonce could be replayed.
+	bootstrapResetNonceTTL = 5 * time.Minute
+
 	// responseWrappingPolicyName is the name of the fixed policy
 	responseWrappingPolicyName = "response-wrapping"
 
@@ -117,9 +153,11 @@ var (
 	immutablePolicies = []string{
 		"root",
 		responseWrappingPolicyName,
+		bootstrapResetStorageKey,
 	}
 	nonAssignablePolicies = []string{
 		responseWrappingPolicyName,
+		bootstrapResetStorageKey,
 	}
 )
 
@@ -127,13 +165,46 @@ var (
 // manage ACLs associated with them.
 type PolicyStore struct {
 	aclView          *BarrierView
+	rgpView          *BarrierView
+	egpView          *BarrierView
+	tombstoneView    *BarrierView
 	tokenPoliciesLRU *lru.TwoQueueCache
+	rgpLRU           *lru.TwoQueueCache
+	// ruleHashLRU caches the compiled body of a policy (its parsed path
+	// rules and/or Sentinel program), keyed by a stable hash of the raw
+	// policy text. Two policies -- whether stored under different names or
+	// inlined directly onto a token -- that share byte-identical text share
+	// a single compiled entry here instead of each paying to parse and hold
+	// their own copy.
+	ruleHashLRU *lru.TwoQueueCache
 	// This is used to ensure that writes to the store (acl/rgp) or to the egp
 	// path tree don't happen concurrently. We are okay reading stale data so
 	// long as there aren't concurrent writes.
 	modifyLock *sync.RWMutex
 	// Stores whether a token policy is ACL or RGP
 	policyTypeMap sync.Map
+	// egpTree is a radix tree, keyed by mount-relative request path, whose
+	// values are the EGP policies whose path rules match that prefix. It is
+	// rebuilt in its entirety under modifyLock whenever an EGP policy is
+	// written or deleted.
+	egpTree *radix.Tree
+	// modifyIndex is a monotonically increasing counter assigned to every
+	// write or delete, guarded by modifyLock. Performance secondaries poll
+	// ListPoliciesSince using the index to learn what's changed on the
+	// primary.
+	modifyIndex uint64
+	// replicationPollStop, when non-nil, stops the performance-secondary
+	// poller started by startPolicyReplicationPoller. replicationClientLock
+	// guards starting/stopping it, since SetPolicyReplicationClient can be
+	// called again (e.g. on reconnect to a new primary) after setup.
+	replicationPollStop   chan struct{}
+	replicationClientLock sync.Mutex
+
+	// bootstrapReset holds the single-use, time-bound nonce generated by
+	// BootstrapResetIndex and consumed by ResetBootstrap, guarded by
+	// bootstrapResetLock.
+	bootstrapReset     *bootstrapResetChallenge
+	bootstrapResetLock sync.Mutex
 }
 
 // PolicyEntry is used to store a policy by name
@@ -141,18 +212,127 @@ type PolicyEntry struct {
 	Version int
 	Raw     string
 	Type    PolicyType
+
+	// Name is set when an entry is surfaced via ListPoliciesSince; it is
+	// derived from the storage key rather than persisted redundantly.
+	Name string `json:"-"`
+
+	// ModifyIndex is assigned on every SetPolicy/DeletePolicy under
+	// modifyLock, and lets performance secondaries request only what's
+	// changed since their last sync.
+	ModifyIndex uint64
+
+	// ETag is a hash of Raw and Type, recomputed on every
+	// setPolicyInternal write. Callers can pass a previously-seen ETag to
+	// GetPolicyIfChanged to avoid re-fetching and re-parsing a policy that
+	// hasn't changed.
+	ETag string
+
+	// DeletedAt is set (and Raw left empty) when this entry represents a
+	// tombstone for a deleted policy rather than the policy itself.
+	DeletedAt time.Time `json:"deleted_at,omitempty"`
+}
+
+// compiledRule is the shared, immutable result of parsing a policy's raw
+// text. Every PolicyEntry whose Raw hashes the same points at the same
+// compiledRule rather than holding its own copy of the parsed paths.
+type compiledRule struct {
+	paths    []*PathRules
+	sentinel *sentinel.Policy
+}
+
+// ruleHash returns a stable digest of a policy's raw text, used as the key
+// into ruleHashLRU. Blake2b is used rather than SHA-256 because the value is never exposed outside the process and
+// blake2b is meaningfully faster on the policy sizes we see in practice.
+func ruleHash(raw string) string {
+	sum := blake2b.Sum256([]byte(raw))
+	return string(sum[:])
+}
+
+// etagFor computes the ETag stored on a PolicyEntry. Unlike ruleHash, this
+// value is returned to callers (e.g. as an HTTP ETag header), so it's
+// hex-encoded and includes the policy type so that, in principle, an ACL
+// and an EGP policy with identical raw text don't collide.
+func etagFor(raw string, policyType PolicyType) string {
+	sum := blake2b.Sum256([]byte(policyType.String() + "|" + raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// compileRuleFault is a FaultFunc: it is handed to ruleHashLRU lookups and
+// is only invoked on a cache miss, so the (comparatively) expensive HCL or
+// Sentinel parse only ever happens once per unique rule body.
+type compileRuleFault func() (*compiledRule, error)
+
+// getOrCompileRule consults ruleHashLRU for the given raw policy text,
+// calling fault to compile and populate the cache on a miss. This is the
+// single choke point that both named-policy storage (SetPolicy/GetPolicy)
+// and inline token policies (TokenStore) funnel through so that
+// byte-identical policy text is only ever compiled once.
+func (ps *PolicyStore) getOrCompileRule(raw string, policyType PolicyType, fault compileRuleFault) (*compiledRule, error) {
+	if ps.ruleHashLRU == nil {
+		return fault()
+	}
+
+	// Include the policy type in the cache key: an ACL and an EGP policy
+	// that happen to share byte-identical raw text compile to different
+	// things (plain path rules vs. a Sentinel program) and must not share
+	// a cache entry.
+	hash := policyType.String() + "|" + ruleHash(raw)
+	if raw, ok := ps.ruleHashLRU.Get(hash); ok {
+		return raw.(*compiledRule), nil
+	}
+
+	compiled, err := fault()
+	if err != nil {
+		return nil, err
+	}
+
+	ps.ruleHashLRU.Add(hash, compiled)
+	return compiled, nil
+}
+
+// InlineACLPolicy compiles a raw, unnamed ACL policy body -- such as one
+// supplied inline on a token entry rather than stored by name -- through
+// the same rule-hash dedupe path used for named policies.
+func (ps *PolicyStore) InlineACLPolicy(raw string) (*Policy, error) {
+	compiled, err := ps.getOrCompileRule(raw, PolicyTypeACL, func() (*compiledRule, error) {
+		p, err := ParseACLPolicy(raw)
+		if err != nil {
+			return nil, err
+		}
+		return &compiledRule{paths: p.Paths}, nil
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to parse inline policy: {{err}}", err)
+	}
+
+	return &Policy{
+		Raw:   raw,
+		Type:  PolicyTypeACL,
+		Paths: compiled.paths,
+	}, nil
 }
 
 // NewPolicyStore creates a new PolicyStore that is backed
 // using a given view. It used used to durable store and manage named policy.
 func NewPolicyStore(baseView *BarrierView, system logical.SystemView) *PolicyStore {
 	ps := &PolicyStore{
-		aclView:    baseView.SubView(policyACLSubPath),
-		modifyLock: new(sync.RWMutex),
+		aclView:       baseView.SubView(policyACLSubPath),
+		rgpView:       baseView.SubView(policyRGPSubPath),
+		egpView:       baseView.SubView(policyEGPSubPath),
+		tombstoneView: baseView.SubView(policyTombstoneSubPath),
+		modifyLock:    new(sync.RWMutex),
+		egpTree:       radix.New(),
 	}
 	if !system.CachingDisabled() {
 		cache, _ := lru.New2Q(policyCacheSize)
 		ps.tokenPoliciesLRU = cache
+
+		rgpCache, _ := lru.New2Q(policyCacheSize)
+		ps.rgpLRU = rgpCache
+
+		ruleHashCache, _ := lru.New2Q(ruleHashCacheSize)
+		ps.ruleHashLRU = ruleHashCache
 	}
 
 	keys, err := logical.CollectKeys(ps.aclView)
@@ -165,6 +345,34 @@ func NewPolicyStore(baseView *BarrierView, system logical.SystemView) *PolicySto
 	}
 	// Special-case root; doesn't exist on disk but does need to be found
 	ps.policyTypeMap.Store("root", PolicyTypeACL)
+
+	rgpKeys, err := logical.CollectKeys(ps.rgpView)
+	if err != nil {
+		vlogger.Error("error collecting rgp policy keys", "error", err)
+		return nil
+	}
+	for _, key := range rgpKeys {
+		ps.policyTypeMap.Store(ps.sanitizeName(key), PolicyTypeRGP)
+	}
+
+	if err := ps.buildEGPTreeLocked(); err != nil {
+		vlogger.Error("error building egp path tree", "error", err)
+		return nil
+	}
+
+	// Resume modifyIndex from the highest index already in storage so that
+	// indices keep increasing across restarts.
+	entries, _, err := ps.ListPoliciesSince(0)
+	if err != nil {
+		vlogger.Error("error collecting policy modify indices", "error", err)
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.ModifyIndex > ps.modifyIndex {
+			ps.modifyIndex = entry.ModifyIndex
+		}
+	}
+
 	return ps
 }
 
@@ -176,7 +384,15 @@ func (c *Core) setupPolicyStore() error {
 	c.policyStore = NewPolicyStore(c.systemBarrierView, sysView)
 
 	if c.replicationState.HasState(consts.ReplicationPerformanceSecondary) {
-		// Policies will sync from the primary
+		// Policies sync from the primary via a background poller rather
+		// than being created locally. In the common case the replication
+		// subsystem hasn't connected to a primary yet at unseal time, so
+		// this only covers the rare case where a client is already
+		// available; the usual trigger is SetPolicyReplicationClient,
+		// called by the replication subsystem once it connects.
+		if c.policyReplicationClient != nil {
+			c.SetPolicyReplicationClient(c.policyReplicationClient)
+		}
 		return nil
 	}
 
@@ -210,10 +426,89 @@ func (c *Core) setupPolicyStore() error {
 // teardownPolicyStore is used to reverse setupPolicyStore
 // when the vault is being sealed.
 func (c *Core) teardownPolicyStore() error {
+	if c.policyStore != nil {
+		c.policyStore.replicationClientLock.Lock()
+		if c.policyStore.replicationPollStop != nil {
+			close(c.policyStore.replicationPollStop)
+		}
+		c.policyStore.replicationClientLock.Unlock()
+	}
 	c.policyStore = nil
 	return nil
 }
 
+// policyReplicationClient is the narrow interface the policy-store
+// replication poller needs from the replication subsystem in order to ask
+// the performance primary what's changed. The concrete implementation is
+// wired onto Core by the replication subsystem once a secondary has
+// connected to its primary.
+type policyReplicationClient interface {
+	ListPoliciesSince(index uint64) ([]PolicyEntry, uint64, error)
+}
+
+// SetPolicyReplicationClient is called by the replication subsystem once a
+// performance secondary has connected to its primary, and (re)starts the
+// policy-sync poller against it. setupPolicyStore's own check for an
+// already-present client only covers unseal itself; in the ordinary case
+// the replication subsystem connects after unseal has already completed,
+// so this is the call site that actually makes syncing happen. Passing a
+// nil client stops any poller currently running without starting a new
+// one, e.g. when a secondary is demoted or loses its primary.
+func (c *Core) SetPolicyReplicationClient(client policyReplicationClient) {
+	if c.policyStore == nil {
+		return
+	}
+
+	c.policyStore.replicationClientLock.Lock()
+	defer c.policyStore.replicationClientLock.Unlock()
+
+	c.policyReplicationClient = client
+
+	if c.policyStore.replicationPollStop != nil {
+		close(c.policyStore.replicationPollStop)
+		c.policyStore.replicationPollStop = nil
+	}
+
+	if client != nil {
+		c.policyStore.replicationPollStop = c.startPolicyReplicationPoller(client)
+	}
+}
+
+// startPolicyReplicationPoller launches a background goroutine that
+// periodically asks the primary for policies changed since the last poll
+// and applies them locally. It returns a channel that, when closed, stops
+// the poller; used by teardownPolicyStore on seal.
+func (c *Core) startPolicyReplicationPoller(client policyReplicationClient) chan struct{} {
+	stopCh := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(policyReplicationPollInterval)
+		defer ticker.Stop()
+
+		var lastIndex uint64
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				entries, latest, err := client.ListPoliciesSince(lastIndex)
+				if err != nil {
+					vlogger.Error("policy: error polling primary for policy changes", "error", err)
+					continue
+				}
+				for _, entry := range entries {
+					if err := c.policyStore.applyReplicatedEntry(entry); err != nil {
+						vlogger.Error("policy: error applying replicated policy", "name", entry.Name, "error", err)
+					}
+				}
+				lastIndex = latest
+			}
+		}
+	}()
+
+	return stopCh
+}
+
 func (ps *PolicyStore) invalidate(name string, policyType PolicyType) {
 	// This may come with a prefixed "/" due to joining the file path
 	saneName := strings.TrimPrefix(name, "/")
@@ -226,6 +521,19 @@ func (ps *PolicyStore) invalidate(name string, policyType PolicyType) {
 			ps.tokenPoliciesLRU.Remove(saneName)
 		}
 
+	case PolicyTypeRGP:
+		if ps.rgpLRU != nil {
+			ps.rgpLRU.Remove(saneName)
+		}
+
+	case PolicyTypeEGP:
+		ps.modifyLock.Lock()
+		if err := ps.buildEGPTreeLocked(); err != nil {
+			vlogger.Error("policy: error rebuilding egp path tree after invalidation", "error", err)
+		}
+		ps.modifyLock.Unlock()
+		return
+
 	default:
 		// Can't do anything
 		return
@@ -257,13 +565,47 @@ func (ps *PolicyStore) SetPolicy(p *Policy) error {
 }
 
 func (ps *PolicyStore) setPolicyInternal(p *Policy) error {
+	return ps.setPolicyInternalIndexed(p, 0)
+}
+
+// setPolicyInternalIndexed is the shared write path for both locally
+// originated writes (remoteIndex == 0, in which case the next local
+// modifyIndex is assigned) and writes applied from a performance primary
+// during replication (remoteIndex is the index assigned on the primary,
+// and is preserved rather than reassigned).
+func (ps *PolicyStore) setPolicyInternalIndexed(p *Policy, remoteIndex uint64) error {
 	ps.modifyLock.Lock()
 	defer ps.modifyLock.Unlock()
+
+	// Dedupe the compiled body against any other policy that already
+	// shares this raw text, so the in-memory cache entry we're about to
+	// populate is a thin wrapper around the shared compiled rule rather
+	// than its own copy.
+	compiled, err := ps.getOrCompileRule(p.Raw, p.Type, func() (*compiledRule, error) {
+		return &compiledRule{paths: p.Paths, sentinel: p.Sentinel}, nil
+	})
+	if err != nil {
+		return errwrap.Wrapf("failed to compile policy: {{err}}", err)
+	}
+	p.Paths = compiled.paths
+	p.Sentinel = compiled.sentinel
+	p.ETag = etagFor(p.Raw, p.Type)
+
+	index := remoteIndex
+	if index == 0 {
+		ps.modifyIndex++
+		index = ps.modifyIndex
+	} else if index > ps.modifyIndex {
+		ps.modifyIndex = index
+	}
+
 	// Create the entry
 	entry, err := logical.StorageEntryJSON(p.Name, &PolicyEntry{
-		Version: 2,
-		Raw:     p.Raw,
-		Type:    p.Type,
+		Version:     2,
+		Raw:         p.Raw,
+		Type:        p.Type,
+		ModifyIndex: index,
+		ETag:        p.ETag,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create entry: %v", err)
@@ -280,6 +622,28 @@ func (ps *PolicyStore) setPolicyInternal(p *Policy) error {
 			ps.tokenPoliciesLRU.Add(p.Name, p)
 		}
 
+	case PolicyTypeRGP:
+		if err := ps.rgpView.Put(entry); err != nil {
+			return errwrap.Wrapf("failed to persist policy: {{err}}", err)
+		}
+		ps.policyTypeMap.Store(p.Name, PolicyTypeRGP)
+
+		if ps.rgpLRU != nil {
+			// Update the LRU cache
+			ps.rgpLRU.Add(p.Name, p)
+		}
+
+	case PolicyTypeEGP:
+		if err := ps.egpView.Put(entry); err != nil {
+			return errwrap.Wrapf("failed to persist policy: {{err}}", err)
+		}
+
+		// The egp tree is keyed by path rather than by name, so it must be
+		// rebuilt in its entirety any time an EGP policy changes.
+		if err := ps.buildEGPTreeLocked(); err != nil {
+			return errwrap.Wrapf("failed to rebuild egp path tree: {{err}}", err)
+		}
+
 	default:
 		return fmt.Errorf("unknown policy type, cannot set")
 	}
@@ -287,6 +651,76 @@ func (ps *PolicyStore) setPolicyInternal(p *Policy) error {
 	return nil
 }
 
+// buildEGPTreeLocked walks the egp view, parsing each stored EGP policy and
+// inserting it into a fresh radix tree keyed by each of its path rules. The
+// caller must hold modifyLock.
+func (ps *PolicyStore) buildEGPTreeLocked() error {
+	tree := radix.New()
+
+	keys, err := logical.CollectKeys(ps.egpView)
+	if err != nil {
+		return errwrap.Wrapf("failed to collect egp policy keys: {{err}}", err)
+	}
+
+	for _, key := range keys {
+		name := ps.sanitizeName(key)
+
+		out, err := ps.egpView.Get(name)
+		if err != nil {
+			return errwrap.Wrapf("failed to read egp policy: {{err}}", err)
+		}
+		if out == nil {
+			continue
+		}
+
+		policyEntry := new(PolicyEntry)
+		if err := out.DecodeJSON(policyEntry); err != nil {
+			return errwrap.Wrapf("failed to parse egp policy: {{err}}", err)
+		}
+
+		compiled, err := ps.getOrCompileRule(policyEntry.Raw, PolicyTypeEGP, func() (*compiledRule, error) {
+			p, err := ParseEGPPolicy(policyEntry.Raw)
+			if err != nil {
+				return nil, err
+			}
+			return &compiledRule{paths: p.Paths, sentinel: p.Sentinel}, nil
+		})
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to parse egp policy %q: {{err}}", name), err)
+		}
+		policy := &Policy{
+			Name:     name,
+			Type:     PolicyTypeEGP,
+			Raw:      policyEntry.Raw,
+			Paths:    compiled.paths,
+			Sentinel: compiled.sentinel,
+		}
+
+		for _, path := range policy.Paths {
+			// Mirror NewACL's glob handling: a path like "secret/*" is
+			// stored trimmed of its trailing "*" so that WalkPath's
+			// string-prefix match against a real request path like
+			// "secret/foo" actually finds it. Without this, every glob
+			// EGP path persists and parses fine but silently never
+			// matches a request.
+			prefix := path.Prefix
+			if path.Glob {
+				prefix = strings.TrimSuffix(prefix, "*")
+			}
+
+			var policies []*Policy
+			if raw, ok := tree.Get(prefix); ok {
+				policies = raw.([]*Policy)
+			}
+			policies = append(policies, policy)
+			tree.Insert(prefix, policies)
+		}
+	}
+
+	ps.egpTree = tree
+	return nil
+}
+
 // GetPolicy is used to fetch the named policy
 func (ps *PolicyStore) GetPolicy(name string, policyType PolicyType) (*Policy, error) {
 	defer metrics.MeasureSince([]string{"policy", "get_policy"}, time.Now())
@@ -300,8 +734,12 @@ func (ps *PolicyStore) GetPolicy(name string, policyType PolicyType) (*Policy, e
 	case PolicyTypeACL:
 		cache = ps.tokenPoliciesLRU
 		view = ps.aclView
+	case PolicyTypeRGP:
+		cache = ps.rgpLRU
+		view = ps.rgpView
+	case PolicyTypeEGP:
+		view = ps.egpView
 	case PolicyTypeToken:
-		cache = ps.tokenPoliciesLRU
 		val, ok := ps.policyTypeMap.Load(name)
 		if !ok {
 			// Doesn't exist
@@ -310,7 +748,11 @@ func (ps *PolicyStore) GetPolicy(name string, policyType PolicyType) (*Policy, e
 		policyType = val.(PolicyType)
 		switch policyType {
 		case PolicyTypeACL:
+			cache = ps.tokenPoliciesLRU
 			view = ps.aclView
+		case PolicyTypeRGP:
+			cache = ps.rgpLRU
+			view = ps.rgpView
 		default:
 			return nil, fmt.Errorf("invalid type of policy in type map: %s", policyType)
 		}
@@ -325,7 +767,7 @@ func (ps *PolicyStore) GetPolicy(name string, policyType PolicyType) (*Policy, e
 
 	// Special case the root policy
 	if policyType == PolicyTypeACL && name == "root" {
-		p := &Policy{Name: "root"}
+		p := &Policy{Name: "root", ETag: "root"}
 		if cache != nil {
 			cache.Add(p.Name, p)
 		}
@@ -365,17 +807,64 @@ func (ps *PolicyStore) GetPolicy(name string, policyType PolicyType) (*Policy, e
 	policy.Type = policyEntry.Type
 	switch policyEntry.Type {
 	case PolicyTypeACL:
-		// Parse normally
-		p, err := ParseACLPolicy(policyEntry.Raw)
+		// Parse normally, deduping against any other policy that shares
+		// the same raw text
+		compiled, err := ps.getOrCompileRule(policyEntry.Raw, PolicyTypeACL, func() (*compiledRule, error) {
+			p, err := ParseACLPolicy(policyEntry.Raw)
+			if err != nil {
+				return nil, err
+			}
+			return &compiledRule{paths: p.Paths}, nil
+		})
 		if err != nil {
 			return nil, errwrap.Wrapf("failed to parse policy: {{err}}", err)
 		}
-		policy.Paths = p.Paths
+		policy.Paths = compiled.paths
+		policy.ETag = policyEntry.ETag
 		// Reset this in case they set the name in the policy itself
 		policy.Name = name
 
 		ps.policyTypeMap.Store(name, PolicyTypeACL)
 
+	case PolicyTypeRGP:
+		// Parse as a Sentinel role-governing policy; it carries no ACL
+		// paths of its own, only a compiled Sentinel program
+		compiled, err := ps.getOrCompileRule(policyEntry.Raw, PolicyTypeRGP, func() (*compiledRule, error) {
+			sentinelPolicy, err := ParseSentinelPolicy(policyEntry.Raw)
+			if err != nil {
+				return nil, err
+			}
+			return &compiledRule{sentinel: sentinelPolicy}, nil
+		})
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to parse policy: {{err}}", err)
+		}
+		policy.Sentinel = compiled.sentinel
+		policy.ETag = policyEntry.ETag
+		// Reset this in case they set the name in the policy itself
+		policy.Name = name
+
+		ps.policyTypeMap.Store(name, PolicyTypeRGP)
+
+	case PolicyTypeEGP:
+		// EGP policies live in the egp path tree rather than the by-name
+		// cache, but may still be looked up directly by name (e.g. for
+		// sys/policies/egp/:name)
+		compiled, err := ps.getOrCompileRule(policyEntry.Raw, PolicyTypeEGP, func() (*compiledRule, error) {
+			p, err := ParseEGPPolicy(policyEntry.Raw)
+			if err != nil {
+				return nil, err
+			}
+			return &compiledRule{paths: p.Paths, sentinel: p.Sentinel}, nil
+		})
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to parse policy: {{err}}", err)
+		}
+		policy.Paths = compiled.paths
+		policy.Sentinel = compiled.sentinel
+		policy.ETag = policyEntry.ETag
+		policy.Name = name
+
 	default:
 		return nil, fmt.Errorf("unknown policy type %q", policyEntry.Type.String())
 	}
@@ -388,6 +877,65 @@ func (ps *PolicyStore) GetPolicy(name string, policyType PolicyType) (*Policy, e
 	return policy, nil
 }
 
+// GetPolicyIfChanged fetches the named policy like GetPolicy, but returns
+// (nil, false, nil) if etag matches the policy's current ETag, letting a
+// caller that already holds a copy of the policy -- an HTTP client sending
+// If-None-Match, or the token-store ACL construction path re-checking a
+// cached policy -- skip the work of re-fetching and re-cloning it. It also
+// returns (nil, false, nil) when the policy does not exist.
+func (ps *PolicyStore) GetPolicyIfChanged(name string, policyType PolicyType, etag string) (*Policy, bool, error) {
+	name = ps.sanitizeName(name)
+
+	if etag != "" {
+		if policyType == PolicyTypeACL && name == "root" {
+			if etag == "root" {
+				return nil, false, nil
+			}
+		} else {
+			var view *BarrierView
+			switch policyType {
+			case PolicyTypeACL:
+				view = ps.aclView
+			case PolicyTypeRGP:
+				view = ps.rgpView
+			case PolicyTypeEGP:
+				view = ps.egpView
+			default:
+				return nil, false, fmt.Errorf("unknown policy type %q", policyType)
+			}
+
+			ps.modifyLock.RLock()
+			out, err := view.Get(name)
+			ps.modifyLock.RUnlock()
+			if err != nil {
+				return nil, false, errwrap.Wrapf("failed to read policy: {{err}}", err)
+			}
+			if out == nil {
+				return nil, false, nil
+			}
+
+			entry := new(PolicyEntry)
+			if err := out.DecodeJSON(entry); err != nil {
+				return nil, false, errwrap.Wrapf("failed to parse policy: {{err}}", err)
+			}
+
+			if etag == entry.ETag {
+				return nil, false, nil
+			}
+		}
+	}
+
+	policy, err := ps.GetPolicy(name, policyType)
+	if err != nil {
+		return nil, false, err
+	}
+	if policy == nil {
+		return nil, false, nil
+	}
+
+	return policy, true, nil
+}
+
 // ListPolicies is used to list the available policies
 func (ps *PolicyStore) ListPolicies(policyType PolicyType) ([]string, error) {
 	defer metrics.MeasureSince([]string{"policy", "list_policies"}, time.Now())
@@ -398,6 +946,10 @@ func (ps *PolicyStore) ListPolicies(policyType PolicyType) ([]string, error) {
 	switch policyType {
 	case PolicyTypeACL:
 		keys, err = logical.CollectKeys(ps.aclView)
+	case PolicyTypeRGP:
+		keys, err = logical.CollectKeys(ps.rgpView)
+	case PolicyTypeEGP:
+		keys, err = logical.CollectKeys(ps.egpView)
 	default:
 		return nil, fmt.Errorf("unknown policy type %s", policyType)
 	}
@@ -453,21 +1005,278 @@ func (ps *PolicyStore) DeletePolicy(name string, policyType PolicyType) error {
 
 		ps.policyTypeMap.Delete(name)
 
+	case PolicyTypeRGP:
+		err := ps.rgpView.Delete(name)
+		if err != nil {
+			return errwrap.Wrapf("failed to delete policy: {{err}}", err)
+		}
+
+		if ps.rgpLRU != nil {
+			ps.rgpLRU.Remove(name)
+		}
+
+		ps.policyTypeMap.Delete(name)
+
+	case PolicyTypeEGP:
+		err := ps.egpView.Delete(name)
+		if err != nil {
+			return errwrap.Wrapf("failed to delete policy: {{err}}", err)
+		}
+
+		if err := ps.buildEGPTreeLocked(); err != nil {
+			return errwrap.Wrapf("failed to rebuild egp path tree: {{err}}", err)
+		}
+
+	default:
+		return fmt.Errorf("unknown policy type %q, cannot delete", policyType)
+	}
+
+	if err := ps.writeTombstoneLocked(name, policyType); err != nil {
+		return errwrap.Wrapf("failed to record policy deletion: {{err}}", err)
 	}
+
 	return nil
 }
 
+// writeTombstoneLocked records that name/policyType was deleted at the
+// current modifyIndex, so that ListPoliciesSince can report the deletion
+// to performance secondaries that haven't seen it yet. The caller must
+// hold modifyLock.
+//
+// Tombstones are never garbage collected: there's no tracking of which
+// secondaries exist or what index each has synced past, so there's no safe
+// point at which a tombstone is known to be acked everywhere. tombstoneView
+// grows by one entry per deletion for the life of the cluster; pruning it
+// would require a real ack path (each secondary reporting its synced index
+// back to the primary) that doesn't exist yet.
+func (ps *PolicyStore) writeTombstoneLocked(name string, policyType PolicyType) error {
+	ps.modifyIndex++
+
+	entry, err := logical.StorageEntryJSON(name, &PolicyEntry{
+		Type:        policyType,
+		ModifyIndex: ps.modifyIndex,
+		DeletedAt:   time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create tombstone entry: %v", err)
+	}
+
+	return ps.tombstoneView.Put(entry)
+}
+
+// ListPoliciesSince returns every PolicyEntry (including tombstones for
+// deletions) whose ModifyIndex is greater than index, along with the
+// highest ModifyIndex currently known. Performance secondaries poll this
+// to learn what's changed on the primary since their last sync.
+func (ps *PolicyStore) ListPoliciesSince(index uint64) ([]PolicyEntry, uint64, error) {
+	ps.modifyLock.RLock()
+	defer ps.modifyLock.RUnlock()
+
+	var changed []PolicyEntry
+
+	views := map[PolicyType]*BarrierView{
+		PolicyTypeACL: ps.aclView,
+		PolicyTypeRGP: ps.rgpView,
+		PolicyTypeEGP: ps.egpView,
+	}
+	for policyType, view := range views {
+		keys, err := logical.CollectKeys(view)
+		if err != nil {
+			return nil, 0, errwrap.Wrapf("failed to collect policy keys: {{err}}", err)
+		}
+		for _, key := range keys {
+			name := ps.sanitizeName(key)
+			out, err := view.Get(name)
+			if err != nil {
+				return nil, 0, errwrap.Wrapf("failed to read policy: {{err}}", err)
+			}
+			if out == nil {
+				continue
+			}
+			entry := new(PolicyEntry)
+			if err := out.DecodeJSON(entry); err != nil {
+				return nil, 0, errwrap.Wrapf("failed to parse policy: {{err}}", err)
+			}
+			if entry.ModifyIndex > index {
+				entry.Name = name
+				entry.Type = policyType
+				changed = append(changed, *entry)
+			}
+		}
+	}
+
+	tombstoneKeys, err := logical.CollectKeys(ps.tombstoneView)
+	if err != nil {
+		return nil, 0, errwrap.Wrapf("failed to collect tombstones: {{err}}", err)
+	}
+	for _, key := range tombstoneKeys {
+		name := ps.sanitizeName(key)
+		out, err := ps.tombstoneView.Get(name)
+		if err != nil {
+			return nil, 0, errwrap.Wrapf("failed to read tombstone: {{err}}", err)
+		}
+		if out == nil {
+			continue
+		}
+		entry := new(PolicyEntry)
+		if err := out.DecodeJSON(entry); err != nil {
+			return nil, 0, errwrap.Wrapf("failed to parse tombstone: {{err}}", err)
+		}
+		if entry.ModifyIndex > index {
+			entry.Name = name
+			changed = append(changed, *entry)
+		}
+	}
+
+	return changed, ps.modifyIndex, nil
+}
+
+// ReplicationStatus returns telemetry describing the current state of
+// policy replication, surfaced on the primary and on secondaries at
+// sys/replication/policies/status.
+func (ps *PolicyStore) ReplicationStatus() map[string]interface{} {
+	ps.replicationClientLock.Lock()
+	polling := ps.replicationPollStop != nil
+	ps.replicationClientLock.Unlock()
+
+	ps.modifyLock.RLock()
+	defer ps.modifyLock.RUnlock()
+
+	return map[string]interface{}{
+		"modify_index": ps.modifyIndex,
+		"polling":      polling,
+	}
+}
+
+// applyReplicatedEntry writes a PolicyEntry received from ListPoliciesSince
+// on the primary into local storage, bypassing the immutablePolicies check
+// since replicated traffic must be able to apply root/response-wrapping
+// updates that originated on the primary.
+func (ps *PolicyStore) applyReplicatedEntry(entry PolicyEntry) error {
+	if !entry.DeletedAt.IsZero() {
+		ps.modifyLock.Lock()
+		defer ps.modifyLock.Unlock()
+
+		var view *BarrierView
+		switch entry.Type {
+		case PolicyTypeACL:
+			view = ps.aclView
+		case PolicyTypeRGP:
+			view = ps.rgpView
+		case PolicyTypeEGP:
+			view = ps.egpView
+		default:
+			return fmt.Errorf("unknown policy type %q in replicated tombstone", entry.Type)
+		}
+
+		if err := view.Delete(entry.Name); err != nil {
+			return errwrap.Wrapf("failed to delete replicated policy: {{err}}", err)
+		}
+
+		switch entry.Type {
+		case PolicyTypeACL:
+			ps.policyTypeMap.Delete(entry.Name)
+			if ps.tokenPoliciesLRU != nil {
+				ps.tokenPoliciesLRU.Remove(entry.Name)
+			}
+		case PolicyTypeRGP:
+			ps.policyTypeMap.Delete(entry.Name)
+			if ps.rgpLRU != nil {
+				ps.rgpLRU.Remove(entry.Name)
+			}
+		case PolicyTypeEGP:
+			if err := ps.buildEGPTreeLocked(); err != nil {
+				return errwrap.Wrapf("failed to rebuild egp path tree: {{err}}", err)
+			}
+		}
+
+		if entry.ModifyIndex > ps.modifyIndex {
+			ps.modifyIndex = entry.ModifyIndex
+		}
+
+		return nil
+	}
+
+	p := &Policy{
+		Name: entry.Name,
+		Raw:  entry.Raw,
+		Type: entry.Type,
+	}
+
+	// Route through the same rule-hash cache GetPolicy uses rather than
+	// parsing unconditionally: a replicated policy whose raw text is
+	// byte-identical to one already compiled locally (its own prior
+	// version, or another policy entirely) should reuse that compiled
+	// rule instead of silently defeating the cache added for this store.
+	switch entry.Type {
+	case PolicyTypeACL:
+		compiled, err := ps.getOrCompileRule(entry.Raw, PolicyTypeACL, func() (*compiledRule, error) {
+			parsed, err := ParseACLPolicy(entry.Raw)
+			if err != nil {
+				return nil, err
+			}
+			return &compiledRule{paths: parsed.Paths}, nil
+		})
+		if err != nil {
+			return errwrap.Wrapf("failed to parse replicated policy: {{err}}", err)
+		}
+		p.Paths = compiled.paths
+	case PolicyTypeRGP:
+		compiled, err := ps.getOrCompileRule(entry.Raw, PolicyTypeRGP, func() (*compiledRule, error) {
+			parsed, err := ParseSentinelPolicy(entry.Raw)
+			if err != nil {
+				return nil, err
+			}
+			return &compiledRule{sentinel: parsed}, nil
+		})
+		if err != nil {
+			return errwrap.Wrapf("failed to parse replicated policy: {{err}}", err)
+		}
+		p.Sentinel = compiled.sentinel
+	case PolicyTypeEGP:
+		compiled, err := ps.getOrCompileRule(entry.Raw, PolicyTypeEGP, func() (*compiledRule, error) {
+			parsed, err := ParseEGPPolicy(entry.Raw)
+			if err != nil {
+				return nil, err
+			}
+			return &compiledRule{paths: parsed.Paths, sentinel: parsed.Sentinel}, nil
+		})
+		if err != nil {
+			return errwrap.Wrapf("failed to parse replicated policy: {{err}}", err)
+		}
+		p.Paths = compiled.paths
+		p.Sentinel = compiled.sentinel
+	default:
+		return fmt.Errorf("unknown policy type %q in replicated policy", entry.Type)
+	}
+
+	return ps.setPolicyInternalIndexed(p, entry.ModifyIndex)
+}
+
 // ACL is used to return an ACL which is built using the
-// named policies.
+// named policies. In addition to the ACL policies, any RGP policies
+// attached to the token are loaded so that the resulting ACL's
+// capabilities checks also run the attached Sentinel rules, and any EGP
+// policies whose path prefix matches the request are evaluated
+// alongside it.
 func (ps *PolicyStore) ACL(names ...string) (*ACL, error) {
 	// Fetch the policies
 	var policies []*Policy
+	var sentinelPolicies []*Policy
 	for _, name := range names {
 		p, err := ps.GetPolicy(name, PolicyTypeToken)
 		if err != nil {
 			return nil, errwrap.Wrapf("failed to get policy: {{err}}", err)
 		}
-		policies = append(policies, p)
+		if p == nil {
+			continue
+		}
+		switch p.Type {
+		case PolicyTypeRGP:
+			sentinelPolicies = append(sentinelPolicies, p)
+		default:
+			policies = append(policies, p)
+		}
 	}
 
 	// Construct the ACL
@@ -475,9 +1284,137 @@ func (ps *PolicyStore) ACL(names ...string) (*ACL, error) {
 	if err != nil {
 		return nil, errwrap.Wrapf("failed to construct ACL: {{err}}", err)
 	}
+
+	acl.sentinelPolicies = sentinelPolicies
+	acl.egpTreeFunc = ps.egpPoliciesForPath
+
 	return acl, nil
 }
 
+// egpPoliciesForPath returns the set of EGP policies whose path rules
+// match the given mount-relative request path. It is wired into each
+// ACL as a closure so that a newly-written EGP policy takes effect for
+// already-constructed ACLs without needing to rebuild them.
+func (ps *PolicyStore) egpPoliciesForPath(path string) []*Policy {
+	ps.modifyLock.RLock()
+	defer ps.modifyLock.RUnlock()
+
+	var matches []*Policy
+	ps.egpTree.WalkPath(path, func(prefix string, raw interface{}) bool {
+		matches = append(matches, raw.([]*Policy)...)
+		return false
+	})
+	return matches
+}
+
+// bootstrapResetEntry records the modifyIndex at which the default and
+// response-wrapping policies were last regenerated via ResetBootstrap.
+type bootstrapResetEntry struct {
+	Index uint64
+}
+
+// bootstrapResetChallenge is the single-use, time-bound proof a caller must
+// present to ResetBootstrap. It replaces an earlier design that used the
+// store's own modifyIndex: that value is bumped by every ordinary
+// SetPolicy/DeletePolicy call cluster-wide and is independently readable
+// via ListPoliciesSince, so presenting it back proved nothing beyond the
+// ability to read cluster state -- not access to anything an attacker
+// couldn't already reach the same way they'd reach bootstrap-reset itself.
+type bootstrapResetChallenge struct {
+	nonce     string
+	expiresAt time.Time
+}
+
+// BootstrapResetIndex generates and returns a fresh single-use nonce that
+// must be echoed back to ResetBootstrap to authorize the reset; generating
+// a new nonce invalidates any previous one.
+//
+// This alone is not an access-control boundary: nothing in this method
+// stops an unauthenticated network caller from reading the nonce and
+// immediately replaying it to ResetBootstrap. sys/policies/bootstrap-reset
+// MUST only be exposed in a way that's reachable solely by an operator who
+// already has access to the server's own host -- e.g. bound to loopback,
+// or requiring the nonce to be read from the server's local log rather
+// than returned in the HTTP response -- exactly as the Nomad/Consul
+// bootstrap-reset precedent this is modeled on requires. The short TTL and
+// single-use consumption below are defense in depth on top of that
+// restriction, not a substitute for it.
+func (ps *PolicyStore) BootstrapResetIndex() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		vlogger.Error("policy: failed to generate bootstrap-reset nonce", "error", err)
+		ps.bootstrapResetLock.Lock()
+		ps.bootstrapReset = nil
+		ps.bootstrapResetLock.Unlock()
+		return ""
+	}
+	nonce := hex.EncodeToString(buf)
+
+	ps.bootstrapResetLock.Lock()
+	ps.bootstrapReset = &bootstrapResetChallenge{
+		nonce:     nonce,
+		expiresAt: time.Now().Add(bootstrapResetNonceTTL),
+	}
+	ps.bootstrapResetLock.Unlock()
+
+	return nonce
+}
+
+// ResetBootstrap recovers from a lost root token and lost admin policies
+// by recreating the default and response-wrapping policies and clearing
+// the cached token policies, so that any token still holding the (now
+// regenerated) default policy regains a sane baseline. providedNonce must
+// match the nonce most recently generated by BootstrapResetIndex and must
+// not have expired; it is consumed on the first call regardless of whether
+// it matches, so an intercepted nonce can't be replayed a second time.
+func (ps *PolicyStore) ResetBootstrap(providedNonce string) error {
+	ps.bootstrapResetLock.Lock()
+	challenge := ps.bootstrapReset
+	ps.bootstrapReset = nil
+	ps.bootstrapResetLock.Unlock()
+
+	switch {
+	case challenge == nil:
+		return fmt.Errorf("no bootstrap-reset nonce has been generated; call BootstrapResetIndex and retry")
+	case time.Now().After(challenge.expiresAt):
+		return fmt.Errorf("bootstrap-reset nonce has expired; generate a new one and retry")
+	case providedNonce != challenge.nonce:
+		return fmt.Errorf("provided bootstrap-reset nonce does not match; generate a new one and retry")
+	}
+
+	if err := ps.createDefaultPolicy(); err != nil {
+		return errwrap.Wrapf("failed to recreate default policy: {{err}}", err)
+	}
+	if err := ps.createResponseWrappingPolicy(); err != nil {
+		return errwrap.Wrapf("failed to recreate response-wrapping policy: {{err}}", err)
+	}
+
+	if ps.tokenPoliciesLRU != nil {
+		ps.tokenPoliciesLRU.Purge()
+	}
+
+	ps.modifyLock.Lock()
+	ps.modifyIndex++
+	resetIndex := ps.modifyIndex
+	ps.modifyLock.Unlock()
+
+	entry, err := logical.StorageEntryJSON(bootstrapResetStorageKey, &bootstrapResetEntry{Index: resetIndex})
+	if err != nil {
+		return fmt.Errorf("failed to create bootstrap-reset entry: %v", err)
+	}
+	if err := ps.aclView.Put(entry); err != nil {
+		return errwrap.Wrapf("failed to persist bootstrap-reset record: {{err}}", err)
+	}
+
+	// The full audit trail entry for this security-sensitive recovery
+	// action is written by the sys/policies/bootstrap-reset HTTP handler,
+	// which has access to the audit broker; we still leave a breadcrumb
+	// here in the server log in case that handler is ever bypassed.
+	vlogger.Warn("policy: default and response-wrapping policies were reset via bootstrap-reset", "index", resetIndex)
+
+	return nil
+}
+
 func (ps *PolicyStore) createDefaultPolicy() error {
 	policy, err := ParseACLPolicy(defaultPolicy)
 	if err != nil {